@@ -1,15 +1,18 @@
 package circulis
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 )
 
 // Predefined errors
 var (
-	ErrClosed = errors.New("circulis: buffer closed")
-	ErrFull   = errors.New("circulis: buffer full")
-	ErrEmpty  = errors.New("circulis: buffer empty")
+	ErrClosed   = errors.New("circulis: buffer closed")
+	ErrFull     = errors.New("circulis: buffer full")
+	ErrEmpty    = errors.New("circulis: buffer empty")
+	ErrReserved = errors.New("circulis: a WriterAt reservation is already outstanding")
 )
 
 // Circulis is a fixed-size, power-of-two-capacity ring buffer.
@@ -21,28 +24,93 @@ type Circulis struct {
 	_        [56]byte // pad out the cache line
 	tail     uint64   // next write index (monotonic)
 	_        [56]byte
-	closed   bool // set when Close() is called
-	blocking bool // if true: Read/Write block on empty/full
+	closed   atomic.Bool // set when Close() is called
+	blocking atomic.Bool // if true: Read/Write block on empty/full
 
 	mu       sync.Mutex
 	notEmpty *sync.Cond // signaled when data is written
 	notFull  *sync.Cond // signaled when data is read
+
+	// lockFree selects the SPSC fast path installed by NewSPSC. head/tail
+	// are then accessed with sync/atomic instead of under mu, and
+	// doorbellR/doorbellW replace notEmpty/notFull for blocking wake-ups.
+	lockFree  bool
+	doorbellR chan struct{} // rung by the writer when data is published
+	doorbellW chan struct{} // rung by the reader when space is freed
+
+	// maxMsgSize bounds WriteMsg payloads; see SetMaxMsgSize.
+	maxMsgSize atomic.Uint32
+
+	// readDeadline/writeDeadline hold a time.Time (zero value = no
+	// deadline); see SetReadDeadline/SetWriteDeadline.
+	readDeadline  atomic.Value
+	writeDeadline atomic.Value
+
+	// spill is non-nil for buffers created with NewWithSpill; it holds
+	// overflow that no longer fits in buf on disk. See circulis_spill.go.
+	spill *spillState
+
+	// reserved is nonzero while a WriterAt reservation is outstanding; it
+	// holds that reservation's size. Only one reservation may be
+	// outstanding at a time, and ordinary Write treats the buffer as full
+	// until it is published, so the reservation's bytes can never be
+	// clobbered by a concurrent writer. See circulis_io.go.
+	reserved uint64
+
+	// growable, growMax, growths, peakInUse, bytesWritten and bytesRead
+	// back the Growable mode installed by WithGrowable; see
+	// circulis_growable.go. They are only maintained when growable is set.
+	growable     bool
+	growMax      uint64
+	growths      int
+	peakInUse    int
+	bytesWritten uint64
+	bytesRead    uint64
 }
 
 // New creates a *Circulis with at least the requested capacity,
-// rounded up to the next power of two. By default it is in blocking mode.
-func New(capacity int) *Circulis {
+// rounded up to the next power of two, applying any Options. By default
+// it is in non-blocking mode; see SetBlocking.
+func New(capacity int, opts ...Option) *Circulis {
 	if capacity < 1 {
 		panic("circulis: capacity must be > 0")
 	}
 	cap2 := nextPowerOfTwo(uint64(capacity))
 	c := &Circulis{
-		buf:      make([]byte, cap2),
-		mask:     cap2 - 1,
-		blocking: false,
+		buf:  make([]byte, cap2),
+		mask: cap2 - 1,
 	}
 	c.notEmpty = sync.NewCond(&c.mu)
 	c.notFull = sync.NewCond(&c.mu)
+	c.maxMsgSize.Store(defaultMaxMsgSize(cap2))
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewSPSC creates a *Circulis tuned for exactly one writer goroutine and
+// one reader goroutine. Instead of the mutex/sync.Cond pair used by New,
+// head and tail are published with atomic loads/stores, so the hot path
+// never takes a lock; blocking Read/Write wait on a small doorbell channel
+// instead. Calling Read from more than one goroutine, or Write from more
+// than one goroutine, is undefined behavior.
+//
+// The multi-producer/multi-consumer instances created by New are unaffected;
+// pick whichever constructor matches the caller's concurrency pattern.
+func NewSPSC(capacity int) *Circulis {
+	if capacity < 1 {
+		panic("circulis: capacity must be > 0")
+	}
+	cap2 := nextPowerOfTwo(uint64(capacity))
+	c := &Circulis{
+		buf:       make([]byte, cap2),
+		mask:      cap2 - 1,
+		lockFree:  true,
+		doorbellR: make(chan struct{}, 1),
+		doorbellW: make(chan struct{}, 1),
+	}
+	c.maxMsgSize.Store(defaultMaxMsgSize(cap2))
 	return c
 }
 
@@ -50,9 +118,14 @@ func New(capacity int) *Circulis {
 // When blocking=false, Write returns ErrFull immediately if no space,
 // Read returns ErrEmpty immediately if no data.
 func (c *Circulis) SetBlocking(blocking bool) {
+	c.blocking.Store(blocking)
+	if c.lockFree {
+		// wake any waiter so it re-checks its condition
+		ring(c.doorbellR)
+		ring(c.doorbellW)
+		return
+	}
 	c.mu.Lock()
-	c.blocking = blocking
-	// wake all waiters so they re-check their conditions
 	c.notEmpty.Broadcast()
 	c.notFull.Broadcast()
 	c.mu.Unlock()
@@ -65,28 +138,80 @@ func (c *Circulis) SetBlocking(blocking bool) {
 // If blocking=false, it writes as much as fits (possibly zero) and returns ErrFull
 // if not all bytes were written.
 func (c *Circulis) Write(p []byte) (n int, err error) {
+	return c.writeCore(nil, p)
+}
+
+// writeCore is the shared implementation behind Write and WriteContext.
+// ctx may be nil, meaning "no cancellation, only the write deadline (if
+// any) applies" — the fast path Write uses, which arms no watcher
+// goroutine when no deadline is set.
+func (c *Circulis) writeCore(ctx context.Context, p []byte) (n int, err error) {
+	if c.lockFree {
+		return c.writeSPSC(ctx, p)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	// If closed, no more writes allowed.
-	if c.closed {
+	if c.closed.Load() {
 		return 0, ErrClosed
 	}
 
 	total := len(p)
 	for n < total {
+		// While spill has an unconsumed backlog, new writes must keep
+		// spilling even if the ring momentarily has free space (e.g. a
+		// Read drained part of the in-memory window): writing into that
+		// free space here would let these bytes be read back before the
+		// still-pending spilled ones, breaking FIFO order.
+		if c.reserved == 0 && c.spill != nil && c.spill.hasBacklog() {
+			written, serr := c.spill.writeOverflow(p[n:total])
+			n += written
+			if serr != nil {
+				return n, serr
+			}
+			continue
+		}
+
 		free := int(uint64(len(c.buf)) - (c.tail - c.head))
+		if c.reserved != 0 {
+			// an outstanding WriterAt reservation claims the rest of the
+			// buffer until it publishes; treat it exactly like a full
+			// buffer so its bytes can never be overwritten, and don't grow
+			// or spill out from under it.
+			free = 0
+		}
 		if free == 0 {
-			// buffer full
-			if !c.blocking {
+			// buffer full: grow instead of blocking, if configured and
+			// there's still room under the configured maximum
+			if c.reserved == 0 && c.growable && uint64(len(c.buf)) < c.growMax {
+				c.grow()
+				continue
+			}
+			// buffer full: spill overflow to disk if configured
+			if c.reserved == 0 && c.spill != nil {
+				written, serr := c.spill.writeOverflow(p[n:total])
+				n += written
+				if serr != nil {
+					return n, serr
+				}
+				continue
+			}
+			if !c.blocking.Load() {
 				if n == 0 {
 					return 0, ErrFull
 				}
 				return n, ErrFull
 			}
-			// wait for readers to consume
+			if err := c.checkDeadline(ctx, &c.writeDeadline); err != nil {
+				return n, err
+			}
+			// wait for readers to consume, or for ctx/the deadline to fire
+			stop := c.armWake(ctx, &c.writeDeadline, c.notFull)
 			c.notFull.Wait()
-			if c.closed {
+			stop()
+			if c.closed.Load() {
 				return n, ErrClosed
 			}
 			continue
@@ -115,6 +240,12 @@ func (c *Circulis) Write(p []byte) (n int, err error) {
 
 		c.tail += uint64(toWrite)
 		n += toWrite
+		if c.growable {
+			c.bytesWritten += uint64(toWrite)
+			if inUse := int(c.tail - c.head); inUse > c.peakInUse {
+				c.peakInUse = inUse
+			}
+		}
 		// wake one reader
 		c.notEmpty.Signal()
 	}
@@ -127,20 +258,41 @@ func (c *Circulis) Write(p []byte) (n int, err error) {
 // If blocking=true, it will block until at least 1 byte is available or the buffer is closed.
 // If blocking=false, it returns ErrEmpty immediately if no data.
 func (c *Circulis) Read(p []byte) (n int, err error) {
+	return c.readCore(nil, p)
+}
+
+// readCore is the shared implementation behind Read and ReadContext. ctx
+// may be nil; see writeCore.
+func (c *Circulis) readCore(ctx context.Context, p []byte) (n int, err error) {
+	if c.lockFree {
+		return c.readSPSC(ctx, p)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	for {
 		available := int(c.tail - c.head)
 		if available == 0 {
+			// buffer empty: page in the oldest spilled chunk if any
+			if paged, perr := c.tryPageIn(); perr != nil {
+				return 0, perr
+			} else if paged {
+				continue
+			}
 			// buffer empty
-			if c.closed {
+			if c.closed.Load() {
 				return 0, ErrClosed
 			}
-			if !c.blocking {
+			if !c.blocking.Load() {
 				return 0, ErrEmpty
 			}
+			if err := c.checkDeadline(ctx, &c.readDeadline); err != nil {
+				return 0, err
+			}
+			stop := c.armWake(ctx, &c.readDeadline, c.notEmpty)
 			c.notEmpty.Wait()
+			stop()
 			continue
 		}
 		// we have at least one byte
@@ -163,22 +315,139 @@ func (c *Circulis) Read(p []byte) (n int, err error) {
 
 		c.head += uint64(toRead)
 		n = toRead
+		if c.growable {
+			c.bytesRead += uint64(toRead)
+		}
 		// wake one writer
 		c.notFull.Signal()
 		return n, nil
 	}
 }
 
+// writeSPSC is the lock-free fast path for buffers created with NewSPSC.
+// It assumes it is only ever called from a single writer goroutine. ctx
+// may be nil, meaning only the write deadline (if any) applies.
+func (c *Circulis) writeSPSC(ctx context.Context, p []byte) (n int, err error) {
+	total := len(p)
+	for n < total {
+		if c.closed.Load() {
+			return n, ErrClosed
+		}
+
+		// tail is only ever written by this goroutine, so a plain read is
+		// fine; head is published by the reader and must be acquired.
+		tail := c.tail
+		head := atomic.LoadUint64(&c.head)
+		free := int(uint64(len(c.buf)) - (tail - head))
+		if free == 0 {
+			if !c.blocking.Load() {
+				if n == 0 {
+					return 0, ErrFull
+				}
+				return n, ErrFull
+			}
+			if err := c.waitDoorbell(ctx, &c.writeDeadline, c.doorbellW); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		toWrite := total - n
+		if toWrite > free {
+			toWrite = free
+		}
+		start := tail & c.mask
+		first := toWrite
+		endSpace := int(uint64(len(c.buf)) - start)
+		if first > endSpace {
+			first = endSpace
+		}
+		copy(c.buf[start:start+uint64(first)], p[n:n+first])
+		second := toWrite - first
+		if second > 0 {
+			copy(c.buf[0:second], p[n+first:n+first+second])
+		}
+
+		tail += uint64(toWrite)
+		n += toWrite
+		// release: publish the new data to the reader
+		atomic.StoreUint64(&c.tail, tail)
+		ring(c.doorbellR)
+	}
+	return n, nil
+}
+
+// readSPSC is the lock-free fast path for buffers created with NewSPSC. ctx
+// may be nil; see writeSPSC.
+func (c *Circulis) readSPSC(ctx context.Context, p []byte) (n int, err error) {
+	for {
+		// head is only ever written by this goroutine; tail is published
+		// by the writer and must be acquired.
+		head := c.head
+		tail := atomic.LoadUint64(&c.tail)
+		available := int(tail - head)
+		if available == 0 {
+			if c.closed.Load() {
+				return 0, ErrClosed
+			}
+			if !c.blocking.Load() {
+				return 0, ErrEmpty
+			}
+			if err := c.waitDoorbell(ctx, &c.readDeadline, c.doorbellR); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		toRead := len(p)
+		if toRead > available {
+			toRead = available
+		}
+		start := head & c.mask
+		first := toRead
+		endSpace := int(uint64(len(c.buf)) - start)
+		if first > endSpace {
+			first = endSpace
+		}
+		copy(p[0:first], c.buf[start:start+uint64(first)])
+		second := toRead - first
+		if second > 0 {
+			copy(p[first:first+second], c.buf[0:second])
+		}
+
+		head += uint64(toRead)
+		n = toRead
+		// release: tell the writer the space is free
+		atomic.StoreUint64(&c.head, head)
+		ring(c.doorbellW)
+		return n, nil
+	}
+}
+
 // Close marks the buffer as closed. Further Write calls return ErrClosed.
 // Any goroutines blocked in Read or Write are awakened and will see ErrClosed once drained.
 func (c *Circulis) Close() {
+	c.closed.Store(true)
+	if c.lockFree {
+		ring(c.doorbellR)
+		ring(c.doorbellW)
+		return
+	}
 	c.mu.Lock()
-	c.closed = true
 	c.notEmpty.Broadcast()
 	c.notFull.Broadcast()
 	c.mu.Unlock()
 }
 
+// ring rings a capacity-1 doorbell channel without blocking if it has
+// already been rung and not yet answered.
+func ring(doorbell chan struct{}) {
+	select {
+	case doorbell <- struct{}{}:
+	default:
+	}
+}
+
 // nextPowerOfTwo returns the smallest power of two >= v.
 func nextPowerOfTwo(v uint64) uint64 {
 	if v == 0 {
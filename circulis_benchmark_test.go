@@ -80,6 +80,42 @@ func BenchmarkRingBuffer_AsyncWrite(b *testing.B) {
 	}
 }
 
+// BenchmarkRingBuffer_SPSC measures the lock-free NewSPSC path under the
+// same single-producer/single-consumer shape as BenchmarkRingBuffer_Sync,
+// for direct comparison against the mutex-based New.
+func BenchmarkRingBuffer_SPSC(b *testing.B) {
+	rb := NewSPSC(1024)
+	data := []byte(strings.Repeat("a", 512))
+	buf := make([]byte, 512)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb.Write(data)
+		rb.Read(buf)
+	}
+}
+
+func BenchmarkRingBuffer_SPSC_AsyncBlocking(b *testing.B) {
+	const sz = 512
+	const buffers = 10
+	rb := NewSPSC(sz * buffers)
+	rb.SetBlocking(true)
+
+	data := []byte(strings.Repeat("a", sz))
+	buf := make([]byte, sz)
+
+	go func() {
+		for {
+			rb.Read(buf)
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rb.Write(data)
+	}
+}
+
 func BenchmarkRingBuffer_AsyncWriteBlocking(b *testing.B) {
 	const sz = 512
 	const buffers = 10
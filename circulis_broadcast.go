@@ -0,0 +1,228 @@
+package circulis
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Broadcaster is a fixed-size, power-of-two-capacity ring buffer in which
+// every registered consumer observes every byte written, rather than the
+// competing-consumer semantics of Circulis. It follows the LMAX Disruptor
+// gating model: the writer's tail may only advance past the slowest
+// consumer's head, so a consumer that falls behind blocks (or fails) the
+// writer instead of silently losing data.
+type Broadcaster struct {
+	buf      []byte
+	mask     uint64
+	tail     uint64 // next write index (monotonic)
+	_        [56]byte
+	closed   atomic.Bool
+	blocking atomic.Bool
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond // signaled when data is written; consumers wait on it
+	notFull  *sync.Cond // signaled when the slowest consumer advances or detaches
+
+	consumers []*broadcastConsumer
+}
+
+// broadcastConsumer is one registered reader's gating position. head lives
+// on its own padded cache line since every Write checks every consumer's
+// head to compute the gate.
+type broadcastConsumer struct {
+	b    *Broadcaster
+	head uint64 // next read index for this consumer (monotonic)
+	_    [56]byte
+
+	closed atomic.Bool // true once Close has detached this consumer
+}
+
+// NewBroadcast creates a *Broadcaster with at least the requested capacity
+// (rounded up to the next power of two) and the given number of consumer
+// slots. By default it is in non-blocking mode. Use Consumer(i) to obtain each
+// registered reader.
+func NewBroadcast(capacity int, consumers int) *Broadcaster {
+	if capacity < 1 {
+		panic("circulis: capacity must be > 0")
+	}
+	if consumers < 1 {
+		panic("circulis: consumers must be > 0")
+	}
+	cap2 := nextPowerOfTwo(uint64(capacity))
+	b := &Broadcaster{
+		buf:  make([]byte, cap2),
+		mask: cap2 - 1,
+	}
+	b.notEmpty = sync.NewCond(&b.mu)
+	b.notFull = sync.NewCond(&b.mu)
+	b.consumers = make([]*broadcastConsumer, consumers)
+	for i := range b.consumers {
+		b.consumers[i] = &broadcastConsumer{b: b}
+	}
+	return b
+}
+
+// Consumer returns the io.ReadCloser for consumer slot i. i must be in
+// [0, consumers) as passed to NewBroadcast.
+func (b *Broadcaster) Consumer(i int) io.ReadCloser {
+	if i < 0 || i >= len(b.consumers) {
+		panic("circulis: invalid consumer index")
+	}
+	return b.consumers[i]
+}
+
+// SetBlocking enables or disables blocking behavior for both Write and
+// every consumer's Read.
+func (b *Broadcaster) SetBlocking(blocking bool) {
+	b.blocking.Store(blocking)
+	b.mu.Lock()
+	b.notEmpty.Broadcast()
+	b.notFull.Broadcast()
+	b.mu.Unlock()
+}
+
+// Write writes up to len(p) bytes, visible to every attached consumer. It
+// blocks (or returns ErrFull in non-blocking mode) when advancing would
+// overrun the slowest attached consumer, so a stalled consumer must either
+// catch up or Close to be removed from the gating set.
+func (b *Broadcaster) Write(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	total := len(p)
+	for n < total {
+		gate := b.minConsumerHead()
+		free := int(uint64(len(b.buf)) - (b.tail - gate))
+		if free == 0 {
+			if !b.blocking.Load() {
+				if n == 0 {
+					return 0, ErrFull
+				}
+				return n, ErrFull
+			}
+			b.notFull.Wait()
+			if b.closed.Load() {
+				return n, ErrClosed
+			}
+			continue
+		}
+
+		toWrite := total - n
+		if toWrite > free {
+			toWrite = free
+		}
+		start := b.tail & b.mask
+		first := toWrite
+		endSpace := int(uint64(len(b.buf)) - start)
+		if first > endSpace {
+			first = endSpace
+		}
+		copy(b.buf[start:start+uint64(first)], p[n:n+first])
+		second := toWrite - first
+		if second > 0 {
+			copy(b.buf[0:second], p[n+first:n+first+second])
+		}
+
+		b.tail += uint64(toWrite)
+		n += toWrite
+		// wake every consumer: all of them can now see the new bytes
+		b.notEmpty.Broadcast()
+	}
+	return n, nil
+}
+
+// minConsumerHead returns the lowest head among attached (non-closed)
+// consumers, or b.tail (i.e. no constraint) if none are attached. Callers
+// must hold b.mu.
+func (b *Broadcaster) minConsumerHead() uint64 {
+	gate := b.tail
+	for _, c := range b.consumers {
+		if c.closed.Load() {
+			continue
+		}
+		if c.head < gate {
+			gate = c.head
+		}
+	}
+	return gate
+}
+
+// Close marks the broadcaster as closed. Further Writes return ErrClosed.
+// Every consumer observes ErrClosed once it has drained the bytes written
+// before Close.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	b.closed.Store(true)
+	b.notEmpty.Broadcast()
+	b.notFull.Broadcast()
+	b.mu.Unlock()
+}
+
+// Read reads up to len(p) bytes not yet seen by this consumer.
+func (bc *broadcastConsumer) Read(p []byte) (n int, err error) {
+	b := bc.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if bc.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	for {
+		available := int(b.tail - bc.head)
+		if available == 0 {
+			if b.closed.Load() {
+				return 0, ErrClosed
+			}
+			if !b.blocking.Load() {
+				return 0, ErrEmpty
+			}
+			b.notEmpty.Wait()
+			if bc.closed.Load() {
+				return 0, ErrClosed
+			}
+			continue
+		}
+
+		toRead := len(p)
+		if toRead > available {
+			toRead = available
+		}
+		start := bc.head & b.mask
+		first := toRead
+		endSpace := int(uint64(len(b.buf)) - start)
+		if first > endSpace {
+			first = endSpace
+		}
+		copy(p[0:first], b.buf[start:start+uint64(first)])
+		second := toRead - first
+		if second > 0 {
+			copy(p[first:first+second], b.buf[0:second])
+		}
+
+		bc.head += uint64(toRead)
+		n = toRead
+		// this consumer's head may now be the slowest no longer, or it may
+		// have just detached the gate entirely — either way the writer
+		// needs to re-check.
+		b.notFull.Broadcast()
+		return n, nil
+	}
+}
+
+// Close detaches this consumer: it is removed from the writer's gating
+// set (so it can no longer stall Write) and further Read calls return
+// ErrClosed.
+func (bc *broadcastConsumer) Close() error {
+	b := bc.b
+	b.mu.Lock()
+	bc.closed.Store(true)
+	b.notFull.Broadcast()
+	b.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,177 @@
+package circulis
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReadContext is like Read, but returns ctx.Err() (with any partial byte
+// count already read) if ctx is done before data becomes available, and
+// os.ErrDeadlineExceeded if the read deadline set by SetDeadline or
+// SetReadDeadline passes first.
+func (c *Circulis) ReadContext(ctx context.Context, p []byte) (int, error) {
+	return c.readCore(ctx, p)
+}
+
+// WriteContext is like Write, but returns ctx.Err() (with any partial byte
+// count already written) if ctx is done before the buffer has room, and
+// os.ErrDeadlineExceeded if the write deadline set by SetDeadline or
+// SetWriteDeadline passes first.
+func (c *Circulis) WriteContext(ctx context.Context, p []byte) (int, error) {
+	return c.writeCore(ctx, p)
+}
+
+// SetDeadline sets both the read and write deadlines, mirroring
+// net.Conn.SetDeadline. A zero time.Time disables the deadline. It affects
+// pending and future Read, Write, ReadContext, and WriteContext calls.
+func (c *Circulis) SetDeadline(t time.Time) {
+	c.SetReadDeadline(t)
+	c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for Read and ReadContext, mirroring
+// net.Conn.SetReadDeadline. A zero time.Time disables the deadline.
+func (c *Circulis) SetReadDeadline(t time.Time) {
+	c.readDeadline.Store(t)
+	c.wakeReaders()
+}
+
+// SetWriteDeadline sets the deadline for Write and WriteContext, mirroring
+// net.Conn.SetWriteDeadline. A zero time.Time disables the deadline.
+func (c *Circulis) SetWriteDeadline(t time.Time) {
+	c.writeDeadline.Store(t)
+	c.wakeWriters()
+}
+
+// wakeReaders/wakeWriters nudge any goroutine currently parked in Read or
+// Write so it re-checks its (possibly just-changed) deadline.
+func (c *Circulis) wakeReaders() {
+	if c.lockFree {
+		ring(c.doorbellR)
+		return
+	}
+	c.mu.Lock()
+	c.notEmpty.Broadcast()
+	c.mu.Unlock()
+}
+
+func (c *Circulis) wakeWriters() {
+	if c.lockFree {
+		ring(c.doorbellW)
+		return
+	}
+	c.mu.Lock()
+	c.notFull.Broadcast()
+	c.mu.Unlock()
+}
+
+// loadDeadline reads a deadline field, reporting whether a non-zero
+// deadline is currently set.
+func loadDeadline(field *atomic.Value) (time.Time, bool) {
+	t, _ := field.Load().(time.Time)
+	return t, !t.IsZero()
+}
+
+// checkDeadline returns ctx.Err() if ctx is done, or os.ErrDeadlineExceeded
+// if the given deadline has passed. ctx may be nil.
+func (c *Circulis) checkDeadline(ctx context.Context, field *atomic.Value) error {
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if dl, ok := loadDeadline(field); ok && !time.Now().Before(dl) {
+		return os.ErrDeadlineExceeded
+	}
+	return nil
+}
+
+// armWake starts a watcher goroutine, if ctx can be cancelled or a
+// deadline is set, that grabs c.mu and broadcasts cond once ctx is done or
+// the deadline passes — the only way to interrupt a blocked sync.Cond.Wait
+// from outside. It must be called with c.mu held, immediately before
+// Wait(); the returned stop func must be called immediately after Wait
+// returns. When neither ctx nor a deadline apply, it is a no-op, so the
+// plain Read/Write path (ctx == nil, no deadline set) pays nothing extra.
+func (c *Circulis) armWake(ctx context.Context, field *atomic.Value, cond *sync.Cond) (stop func()) {
+	var ctxDone <-chan struct{}
+	if ctx != nil {
+		ctxDone = ctx.Done()
+	}
+	dl, hasDL := loadDeadline(field)
+	if ctxDone == nil && !hasDL {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	if hasDL {
+		timer := time.NewTimer(time.Until(dl))
+		go func() {
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctxDone:
+			case <-done:
+				return
+			}
+			c.mu.Lock()
+			cond.Broadcast()
+			c.mu.Unlock()
+		}()
+		return func() { close(done) }
+	}
+
+	go func() {
+		select {
+		case <-ctxDone:
+		case <-done:
+			return
+		}
+		c.mu.Lock()
+		cond.Broadcast()
+		c.mu.Unlock()
+	}()
+	return func() { close(done) }
+}
+
+// waitDoorbell blocks on doorbell (a capacity-1 channel used by the SPSC
+// fast path), waking early if ctx is done or the deadline passes. It
+// returns the reason it woke without a real signal, or nil if doorbell
+// itself fired.
+func (c *Circulis) waitDoorbell(ctx context.Context, field *atomic.Value, doorbell chan struct{}) error {
+	if err := c.checkDeadline(ctx, field); err != nil {
+		return err
+	}
+
+	var ctxDone <-chan struct{}
+	if ctx != nil {
+		ctxDone = ctx.Done()
+	}
+	dl, hasDL := loadDeadline(field)
+	if ctxDone == nil && !hasDL {
+		<-doorbell
+		return nil
+	}
+
+	if hasDL {
+		timer := time.NewTimer(time.Until(dl))
+		defer timer.Stop()
+		select {
+		case <-doorbell:
+			return nil
+		case <-timer.C:
+			return os.ErrDeadlineExceeded
+		case <-ctxDone:
+			return ctx.Err()
+		}
+	}
+	select {
+	case <-doorbell:
+		return nil
+	case <-ctxDone:
+		return ctx.Err()
+	}
+}
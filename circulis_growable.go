@@ -0,0 +1,93 @@
+package circulis
+
+// Option configures a *Circulis at construction time; see New.
+type Option func(*Circulis)
+
+// WithGrowable enables Growable mode: instead of blocking or returning
+// ErrFull once the buffer is full, Write reallocates the underlying
+// buffer to the next power of two, up to max bytes, copying the live
+// region into the new buffer. This removes head-of-line blocking when a
+// slow consumer briefly stalls, at the cost of an occasional allocation
+// and copy on the write path. ErrFull (or blocking) only kicks back in
+// once growth would exceed max. A max that is not a power of two is
+// rounded down to one, so the capacity never exceeds max.
+//
+// WithGrowable is only meaningful with New; it has no effect on NewSPSC
+// buffers, whose fixed-capacity atomic head/tail scheme can't be resized
+// without breaking the lock-free fast path.
+func WithGrowable(max int) Option {
+	growMax := nextPowerOfTwo(uint64(max))
+	if growMax > uint64(max) {
+		growMax /= 2
+	}
+	return func(c *Circulis) {
+		c.growable = true
+		c.growMax = growMax
+	}
+}
+
+// grow doubles the buffer's capacity (capped at growMax), copying the
+// live [head, tail) region into a zero-based layout in the new buffer.
+// Callers must hold c.mu and must have already checked that growth is
+// both enabled and below growMax.
+func (c *Circulis) grow() {
+	oldCap := uint64(len(c.buf))
+	newCap := oldCap * 2
+	if newCap > c.growMax {
+		newCap = c.growMax
+	}
+
+	size := c.tail - c.head
+	newBuf := make([]byte, newCap)
+	start := c.head & c.mask
+	first := size
+	if endSpace := oldCap - start; first > endSpace {
+		first = endSpace
+	}
+	copy(newBuf[0:first], c.buf[start:start+first])
+	second := size - first
+	if second > 0 {
+		copy(newBuf[first:first+second], c.buf[0:second])
+	}
+
+	c.buf = newBuf
+	c.mask = newCap - 1
+	c.head = 0
+	c.tail = size
+	c.growths++
+	if int(size) > c.peakInUse {
+		c.peakInUse = int(size)
+	}
+	// space just opened up for anyone blocked in Write
+	c.notFull.Broadcast()
+}
+
+// Metrics reports Growable-mode statistics, modelled on Pebble's
+// LogWriterMetrics: how many times the buffer has grown, its current
+// capacity, the most bytes it has ever held at once, and the running
+// totals of bytes written and read. Capacity is always reported; the
+// other fields stay zero for buffers not created with WithGrowable.
+// PeakInUse, BytesWritten and BytesRead are updated by Write, Read and
+// their Context variants (PeakInUse also on each growth); bytes moved by
+// ReadFrom, WriteTo, WriteMsg, ReadMsg, ReadMsgInto and WriterAt are not
+// counted.
+type Metrics struct {
+	Growths      int
+	Capacity     int
+	PeakInUse    int
+	BytesWritten uint64
+	BytesRead    uint64
+}
+
+// Metrics returns a snapshot of the buffer's Growable-mode statistics.
+func (c *Circulis) Metrics() Metrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Metrics{
+		Growths:      c.growths,
+		Capacity:     len(c.buf),
+		PeakInUse:    c.peakInUse,
+		BytesWritten: c.bytesWritten,
+		BytesRead:    c.bytesRead,
+	}
+}
@@ -0,0 +1,301 @@
+package circulis
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// WriteTo implements io.WriterTo. It hands w.Write the ring's underlying
+// slices directly (up to two segments per wrap) instead of the
+// make([]byte, n)-then-copy round trip Read would need, advancing past a
+// segment only once w has acknowledged it: a short or failing Write
+// leaves the unwritten bytes in the buffer. WriteTo stops once the buffer
+// is closed and fully drained, returning a nil error, mirroring the
+// io.Reader convention of io.EOF meaning "no more data" — this lets
+// io.Copy(w, c) work as expected.
+//
+// Note that on a buffer created with New, w.Write is called while c's
+// internal lock is held, so a slow w serializes against concurrent
+// Read/Write calls on the same Circulis. On an SPSC buffer no lock is
+// taken; a segment's space is released to the writer only after w.Write
+// returns.
+func (c *Circulis) WriteTo(w io.Writer) (total int64, err error) {
+	if c.lockFree {
+		return c.writeToSPSC(w)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		available := int(c.tail - c.head)
+		if available == 0 {
+			if paged, perr := c.tryPageIn(); perr != nil {
+				return total, perr
+			} else if paged {
+				continue
+			}
+			if c.closed.Load() {
+				return total, nil
+			}
+			if !c.blocking.Load() {
+				return total, ErrEmpty
+			}
+			c.notEmpty.Wait()
+			continue
+		}
+
+		start := c.head & c.mask
+		seg := available
+		if endSpace := int(uint64(len(c.buf)) - start); seg > endSpace {
+			seg = endSpace
+		}
+
+		nw, werr := w.Write(c.buf[start : start+uint64(seg)])
+		c.head += uint64(nw)
+		total += int64(nw)
+		c.notFull.Signal()
+		if werr != nil {
+			return total, werr
+		}
+		if nw < seg {
+			return total, io.ErrShortWrite
+		}
+	}
+}
+
+// writeToSPSC is the WriteTo fast path for buffers created with NewSPSC.
+func (c *Circulis) writeToSPSC(w io.Writer) (total int64, err error) {
+	for {
+		head := c.head
+		tail := atomic.LoadUint64(&c.tail)
+		available := int(tail - head)
+		if available == 0 {
+			if c.closed.Load() {
+				return total, nil
+			}
+			if !c.blocking.Load() {
+				return total, ErrEmpty
+			}
+			if werr := c.waitDoorbell(nil, &c.readDeadline, c.doorbellR); werr != nil {
+				return total, werr
+			}
+			continue
+		}
+
+		start := head & c.mask
+		seg := available
+		if endSpace := int(uint64(len(c.buf)) - start); seg > endSpace {
+			seg = endSpace
+		}
+
+		nw, werr := w.Write(c.buf[start : start+uint64(seg)])
+		head += uint64(nw)
+		atomic.StoreUint64(&c.head, head)
+		ring(c.doorbellW)
+		total += int64(nw)
+		if werr != nil {
+			return total, werr
+		}
+		if nw < seg {
+			return total, io.ErrShortWrite
+		}
+	}
+}
+
+// ReadFrom implements io.ReaderFrom. It hands r.Read the ring's free
+// slices directly (up to two segments per wrap) instead of a temporary
+// buffer, advancing tail by exactly what r acknowledged reading. It reads
+// from r until r returns io.EOF (returned to the caller as a nil error, by
+// the io.Reader convention io.Copy relies on), another error, or — once
+// the buffer is full — either blocks for room (blocking mode) or returns
+// ErrFull (non-blocking mode).
+func (c *Circulis) ReadFrom(r io.Reader) (total int64, err error) {
+	if c.lockFree {
+		return c.readFromSPSC(r)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	for {
+		free := int(uint64(len(c.buf)) - (c.tail - c.head))
+		if c.reserved != 0 {
+			// see writeCore: an outstanding WriterAt reservation claims
+			// the rest of the buffer until it publishes.
+			free = 0
+		}
+		if free == 0 {
+			if c.reserved == 0 && c.growable && uint64(len(c.buf)) < c.growMax {
+				c.grow()
+				continue
+			}
+			if !c.blocking.Load() {
+				return total, ErrFull
+			}
+			c.notFull.Wait()
+			if c.closed.Load() {
+				return total, ErrClosed
+			}
+			continue
+		}
+
+		start := c.tail & c.mask
+		seg := free
+		if endSpace := int(uint64(len(c.buf)) - start); seg > endSpace {
+			seg = endSpace
+		}
+
+		nr, rerr := r.Read(c.buf[start : start+uint64(seg)])
+		c.tail += uint64(nr)
+		total += int64(nr)
+		c.notEmpty.Signal()
+		if rerr != nil {
+			if rerr == io.EOF {
+				return total, nil
+			}
+			return total, rerr
+		}
+	}
+}
+
+// readFromSPSC is the ReadFrom fast path for buffers created with NewSPSC.
+func (c *Circulis) readFromSPSC(r io.Reader) (total int64, err error) {
+	if c.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	for {
+		tail := c.tail
+		head := atomic.LoadUint64(&c.head)
+		free := int(uint64(len(c.buf)) - (tail - head))
+		if free == 0 {
+			if !c.blocking.Load() {
+				return total, ErrFull
+			}
+			if werr := c.waitDoorbell(nil, &c.writeDeadline, c.doorbellW); werr != nil {
+				return total, werr
+			}
+			continue
+		}
+
+		start := tail & c.mask
+		seg := free
+		if endSpace := int(uint64(len(c.buf)) - start); seg > endSpace {
+			seg = endSpace
+		}
+
+		nr, rerr := r.Read(c.buf[start : start+uint64(seg)])
+		tail += uint64(nr)
+		atomic.StoreUint64(&c.tail, tail)
+		ring(c.doorbellR)
+		total += int64(nr)
+		if rerr != nil {
+			if rerr == io.EOF {
+				return total, nil
+			}
+			return total, rerr
+		}
+	}
+}
+
+// WriterAt reserves size bytes of the ring's currently-free space and
+// returns an io.WriterAt over that reservation, so producers that already
+// know byte offsets (e.g. parallel HTTP range fetches) can scatter-write
+// into it directly, matching the streaming pattern used by Arvados's
+// keepstore. On a buffer created with WithGrowable, it grows the buffer
+// (as Write does) if the reservation doesn't fit yet but would once grown.
+// It returns ErrFull if size still exceeds the space available after that,
+// and ErrReserved if another reservation is already outstanding: only one
+// WriterAt reservation is allowed at a time.
+//
+// The reservation is exclusive: until it is fully written and published,
+// ordinary Write treats the buffer as full (blocking, or returning
+// ErrFull in non-blocking mode) rather than allocating from the space
+// behind the reservation, so the reservation's bytes can never be
+// clobbered by a concurrent Write. Growable and spill-to-disk behavior
+// are likewise suspended for the duration of the reservation.
+//
+// The reservation assumes disjoint, full-coverage writes: Read won't see
+// any of it until every byte in [0, size) has been written exactly once.
+// WriteAt does not detect overlapping or missing offsets.
+//
+// WriterAt is only available on buffers created with New; NewSPSC buffers
+// don't support it.
+func (c *Circulis) WriterAt(size int) (io.WriterAt, error) {
+	if c.lockFree {
+		panic("circulis: WriterAt is not supported on NewSPSC buffers")
+	}
+	if size < 0 {
+		panic("circulis: WriterAt size must be >= 0")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Load() {
+		return nil, ErrClosed
+	}
+	if c.reserved != 0 {
+		return nil, ErrReserved
+	}
+	free := int(uint64(len(c.buf)) - (c.tail - c.head))
+	for size > free && c.growable && uint64(len(c.buf)) < c.growMax {
+		c.grow()
+		free = int(uint64(len(c.buf)) - (c.tail - c.head))
+	}
+	if size > free {
+		return nil, ErrFull
+	}
+	c.reserved = uint64(size)
+	return &ringWriterAt{c: c, base: c.tail, size: size}, nil
+}
+
+// ringWriterAt is the io.WriterAt returned by (*Circulis).WriterAt.
+type ringWriterAt struct {
+	c    *Circulis
+	base uint64
+	size int
+
+	mu      sync.Mutex
+	written int
+}
+
+// WriteAt copies p into the reservation at offset off and, once every
+// byte of the reservation has been written, publishes it by advancing the
+// ring's tail and releasing the reservation, so Read (and ordinary Write,
+// which was blocked behind it) can proceed.
+func (wa *ringWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 || int(off)+len(p) > wa.size {
+		return 0, ErrFull
+	}
+	c := wa.c
+
+	c.mu.Lock()
+	if c.closed.Load() {
+		c.mu.Unlock()
+		return 0, ErrClosed
+	}
+	c.writeRaw(wa.base+uint64(off), p)
+	c.mu.Unlock()
+
+	wa.mu.Lock()
+	wa.written += len(p)
+	done := wa.written >= wa.size
+	wa.mu.Unlock()
+
+	if done {
+		c.mu.Lock()
+		c.tail = wa.base + uint64(wa.size)
+		c.reserved = 0
+		c.notEmpty.Broadcast()
+		c.notFull.Broadcast()
+		c.mu.Unlock()
+	}
+	return len(p), nil
+}
@@ -0,0 +1,316 @@
+package circulis
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// ErrMsgTooLarge is returned by WriteMsg when a message exceeds the
+// buffer's configured maximum message size.
+var ErrMsgTooLarge = errors.New("circulis: message too large")
+
+// msgHeaderLen is the size, in bytes, of the big-endian length prefix
+// written ahead of every record by WriteMsg.
+const msgHeaderLen = 4
+
+// defaultMaxMsgSize returns the largest payload that could ever fit in a
+// buffer of capacity cap2, i.e. the whole buffer minus the header.
+func defaultMaxMsgSize(cap2 uint64) uint32 {
+	if cap2 <= msgHeaderLen {
+		return 0
+	}
+	return uint32(cap2) - msgHeaderLen
+}
+
+// SetMaxMsgSize overrides the maximum payload WriteMsg will accept. It
+// defaults to the largest message that could ever fit in the buffer's
+// capacity. Lowering it bounds the allocation ReadMsg makes per call.
+func (c *Circulis) SetMaxMsgSize(n int) {
+	c.maxMsgSize.Store(uint32(n))
+}
+
+// WriteMsg writes p as a single length-prefixed record, so a matching
+// ReadMsg call returns it back with its original boundaries intact. Each
+// record is preceded by a fixed 4-byte big-endian length header; header and
+// payload are written as one atomic unit (under the existing mutex, or as a
+// single release on the SPSC fast path) so concurrent writers can never
+// interleave a torn length with someone else's payload.
+//
+// It never writes a partial record: if the record doesn't fit as a whole
+// and the buffer is non-blocking, it returns ErrFull without writing
+// anything. It returns ErrMsgTooLarge if p exceeds the configured maximum
+// message size (see SetMaxMsgSize).
+func (c *Circulis) WriteMsg(p []byte) error {
+	if uint32(len(p)) > c.maxMsgSize.Load() {
+		return ErrMsgTooLarge
+	}
+	var hdr [msgHeaderLen]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(p)))
+	total := uint64(msgHeaderLen + len(p))
+
+	if c.lockFree {
+		return c.writeMsgSPSC(hdr[:], p, total)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		if c.closed.Load() {
+			return ErrClosed
+		}
+		free := uint64(len(c.buf)) - (c.tail - c.head)
+		if c.reserved != 0 {
+			// see writeCore: an outstanding WriterAt reservation claims
+			// the rest of the buffer until it publishes.
+			free = 0
+		}
+		if free < total {
+			if !c.blocking.Load() {
+				return ErrFull
+			}
+			c.notFull.Wait()
+			continue
+		}
+		pos := c.writeRaw(c.tail, hdr[:])
+		pos = c.writeRaw(pos, p)
+		c.tail = pos
+		c.notEmpty.Signal()
+		return nil
+	}
+}
+
+// writeMsgSPSC is the WriteMsg fast path for buffers created with NewSPSC.
+func (c *Circulis) writeMsgSPSC(hdr, payload []byte, total uint64) error {
+	for {
+		if c.closed.Load() {
+			return ErrClosed
+		}
+		head := atomic.LoadUint64(&c.head)
+		free := uint64(len(c.buf)) - (c.tail - head)
+		if free < total {
+			if !c.blocking.Load() {
+				return ErrFull
+			}
+			<-c.doorbellW
+			continue
+		}
+		pos := c.writeRaw(c.tail, hdr)
+		pos = c.writeRaw(pos, payload)
+		atomic.StoreUint64(&c.tail, pos)
+		ring(c.doorbellR)
+		return nil
+	}
+}
+
+// Peek returns the length of the next complete record without consuming
+// it, so a caller can size a buffer before calling ReadMsgInto. It never
+// blocks: in blocking and non-blocking mode alike it returns ErrEmpty if a
+// full header plus payload is not yet available, and ErrClosed once the
+// buffer is closed and fully drained.
+func (c *Circulis) Peek() (int, error) {
+	if c.lockFree {
+		head := c.head
+		tail := atomic.LoadUint64(&c.tail)
+		return c.peekLocked(head, tail)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		n, err := c.peekLocked(c.head, c.tail)
+		if err != ErrEmpty {
+			return n, err
+		}
+		paged, perr := c.tryPageIn()
+		if perr != nil {
+			return 0, perr
+		}
+		if !paged {
+			return 0, ErrEmpty
+		}
+	}
+}
+
+// peekLocked implements Peek once the caller has a consistent (head, tail)
+// snapshot; it touches no mutable state.
+func (c *Circulis) peekLocked(head, tail uint64) (int, error) {
+	available := tail - head
+	if available < msgHeaderLen {
+		// an empty ring with spilled bytes still on disk is not drained:
+		// report ErrEmpty so the caller pages them in
+		if c.closed.Load() && !(available == 0 && c.spill != nil && c.spill.hasBacklog()) {
+			return 0, ErrClosed
+		}
+		return 0, ErrEmpty
+	}
+	var hdr [msgHeaderLen]byte
+	c.readRaw(head, hdr[:])
+	msgLen := binary.BigEndian.Uint32(hdr[:])
+	if available < uint64(msgHeaderLen)+uint64(msgLen) {
+		if c.closed.Load() {
+			return 0, ErrClosed
+		}
+		return 0, ErrEmpty
+	}
+	return int(msgLen), nil
+}
+
+// ReadMsg blocks (subject to SetBlocking) until a full header and payload
+// are available, then returns exactly one record, allocating a new slice
+// sized to hold it. Use ReadMsgInto to avoid the allocation.
+func (c *Circulis) ReadMsg() ([]byte, error) {
+	n, err := c.waitForMsg()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := c.ReadMsgInto(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// ReadMsgInto blocks (subject to SetBlocking) until a full header and
+// payload are available, then copies exactly one record into buf and
+// returns its length. It returns io.ErrShortBuffer, without consuming the
+// record, if buf is smaller than the next record.
+func (c *Circulis) ReadMsgInto(buf []byte) (int, error) {
+	if c.lockFree {
+		return c.readMsgSPSC(buf)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		n, err := c.peekLocked(c.head, c.tail)
+		if err == ErrEmpty {
+			if paged, perr := c.tryPageIn(); perr != nil {
+				return 0, perr
+			} else if paged {
+				continue
+			}
+			if !c.blocking.Load() {
+				return 0, ErrEmpty
+			}
+			c.notEmpty.Wait()
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n > len(buf) {
+			return 0, io.ErrShortBuffer
+		}
+		pos := c.head + msgHeaderLen
+		c.readRaw(pos, buf[:n])
+		c.head = pos + uint64(n)
+		c.notFull.Signal()
+		return n, nil
+	}
+}
+
+// readMsgSPSC is the ReadMsgInto fast path for buffers created with NewSPSC.
+func (c *Circulis) readMsgSPSC(buf []byte) (int, error) {
+	for {
+		tail := atomic.LoadUint64(&c.tail)
+		n, err := c.peekLocked(c.head, tail)
+		if err == ErrEmpty {
+			if !c.blocking.Load() {
+				return 0, ErrEmpty
+			}
+			<-c.doorbellR
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n > len(buf) {
+			return 0, io.ErrShortBuffer
+		}
+		pos := c.head + msgHeaderLen
+		c.readRaw(pos, buf[:n])
+		pos += uint64(n)
+		atomic.StoreUint64(&c.head, pos)
+		ring(c.doorbellW)
+		return n, nil
+	}
+}
+
+// waitForMsg blocks (subject to SetBlocking) until the next full record's
+// length is known, without consuming it.
+func (c *Circulis) waitForMsg() (int, error) {
+	if c.lockFree {
+		for {
+			tail := atomic.LoadUint64(&c.tail)
+			n, err := c.peekLocked(c.head, tail)
+			if err == ErrEmpty {
+				if !c.blocking.Load() {
+					return 0, ErrEmpty
+				}
+				<-c.doorbellR
+				continue
+			}
+			return n, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		n, err := c.peekLocked(c.head, c.tail)
+		if err == ErrEmpty {
+			if paged, perr := c.tryPageIn(); perr != nil {
+				return 0, perr
+			} else if paged {
+				continue
+			}
+			if !c.blocking.Load() {
+				return 0, ErrEmpty
+			}
+			c.notEmpty.Wait()
+			continue
+		}
+		return n, err
+	}
+}
+
+// writeRaw copies p into c.buf starting at ring position pos (mod the
+// buffer's capacity) and returns the advanced position. It performs no
+// locking or space checks; callers must already hold the right to write
+// into that region (the mutex, or sole ownership in SPSC mode) and must
+// have already verified there is enough free space.
+func (c *Circulis) writeRaw(pos uint64, p []byte) uint64 {
+	start := pos & c.mask
+	first := len(p)
+	endSpace := int(uint64(len(c.buf)) - start)
+	if first > endSpace {
+		first = endSpace
+	}
+	copy(c.buf[start:start+uint64(first)], p[:first])
+	second := len(p) - first
+	if second > 0 {
+		copy(c.buf[0:second], p[first:])
+	}
+	return pos + uint64(len(p))
+}
+
+// readRaw copies len(p) bytes starting at ring position pos (mod the
+// buffer's capacity) into p and returns the advanced position. Like
+// writeRaw, it performs no locking, space checks, or head/tail mutation.
+func (c *Circulis) readRaw(pos uint64, p []byte) uint64 {
+	start := pos & c.mask
+	first := len(p)
+	endSpace := int(uint64(len(c.buf)) - start)
+	if first > endSpace {
+		first = endSpace
+	}
+	copy(p[:first], c.buf[start:start+uint64(first)])
+	second := len(p) - first
+	if second > 0 {
+		copy(p[first:], c.buf[0:second])
+	}
+	return pos + uint64(len(p))
+}
@@ -0,0 +1,242 @@
+package circulis
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SpillConfig configures the optional disk-backed overflow used by
+// NewWithSpill, letting a Circulis behave as an effectively unbounded
+// queue instead of blocking or returning ErrFull once its in-memory
+// capacity is exhausted.
+type SpillConfig struct {
+	// SpillDir is the directory overflow chunk files are created in.
+	// Defaults to os.TempDir() if empty.
+	SpillDir string
+	// MaxSpillBytes caps total on-disk overflow; once reached, writes that
+	// would spill further return ErrFull. Zero means unlimited.
+	MaxSpillBytes int64
+}
+
+// NewWithSpill creates a *Circulis with at least the requested in-memory
+// capacity (rounded up to the next power of two, exactly as New does)
+// that transparently spills overflow writes to chunk files on disk once
+// that in-memory capacity is full, instead of blocking or returning
+// ErrFull. Reads always prefer the in-memory data and transparently page
+// the oldest spilled chunk back in once it is drained.
+func NewWithSpill(capacity int, cfg SpillConfig) *Circulis {
+	c := New(capacity)
+	dir := cfg.SpillDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	c.spill = &spillState{
+		dir:       dir,
+		maxBytes:  cfg.MaxSpillBytes,
+		chunkSize: len(c.buf),
+	}
+	return c
+}
+
+// tryPageIn pages the oldest spilled chunk back into buf if the ring is
+// currently fully drained and spilling is configured. Callers must hold
+// c.mu. It reports whether data was paged in — in which case the caller
+// should re-check its empty/available condition and loop — or an error
+// from the underlying chunk read. Every path that can observe the ring as
+// empty (Read, Peek/ReadMsgInto/waitForMsg, WriteTo) must call this
+// instead of treating an empty ring as having no more data, or it will
+// miss spilled-but-not-yet-paged-in bytes.
+func (c *Circulis) tryPageIn() (bool, error) {
+	if c.spill == nil || c.tail != c.head {
+		return false, nil
+	}
+	paged, err := c.spill.pageIn(c.buf)
+	if err != nil {
+		return false, err
+	}
+	if paged == 0 {
+		return false, nil
+	}
+	c.head = 0
+	c.tail = uint64(paged)
+	return true, nil
+}
+
+// Compact deletes chunk files that have already been fully paged back
+// into memory and consumed. Spilling never requires Compact to make
+// progress; it just reclaims disk space that pageIn leaves behind.
+func (c *Circulis) Compact() error {
+	if c.spill == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spill.compact()
+}
+
+// spillState holds the on-disk overflow for a single Circulis. All of its
+// methods are called with the owning Circulis's c.mu held.
+type spillState struct {
+	dir       string
+	maxBytes  int64
+	chunkSize int // equal to the owning ring's capacity
+
+	spilled int64 // bytes currently on disk, not yet paged back into buf
+	seq     int
+
+	writeFile *os.File
+	writeOff  int
+
+	pending  []spillChunk // unread chunks, oldest first
+	toDelete []string     // fully-consumed chunk paths awaiting Compact
+}
+
+type spillChunk struct {
+	path string
+	size int
+}
+
+// hasBacklog reports whether any spilled bytes remain unconsumed, either
+// queued as whole pending chunks or still accumulating in the currently
+// open write file. While true, new writes must keep spilling rather than
+// use any momentarily-free ring space, or they would be read back ahead
+// of the still-pending spilled data and break FIFO ordering.
+func (s *spillState) hasBacklog() bool {
+	return s.spilled > 0
+}
+
+// writeOverflow appends p to the spill area, rotating to a new chunk file
+// every chunkSize bytes, and returns how much of p it accepted. It stops
+// short of the full length, returning ErrFull, once maxBytes is reached.
+func (s *spillState) writeOverflow(p []byte) (int, error) {
+	total := len(p)
+	n := 0
+	for n < total {
+		if s.maxBytes > 0 && s.spilled >= s.maxBytes {
+			if n == 0 {
+				return 0, ErrFull
+			}
+			return n, ErrFull
+		}
+		if err := s.ensureWriteFile(); err != nil {
+			return n, err
+		}
+
+		toWrite := total - n
+		if remaining := s.chunkSize - s.writeOff; toWrite > remaining {
+			toWrite = remaining
+		}
+		if s.maxBytes > 0 {
+			if room := s.maxBytes - s.spilled; int64(toWrite) > room {
+				toWrite = int(room)
+			}
+		}
+		if toWrite == 0 {
+			// current chunk is full; finish it and start a new one
+			if err := s.rotateWriteFile(); err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		if _, err := s.writeFile.Write(p[n : n+toWrite]); err != nil {
+			return n, err
+		}
+		s.writeOff += toWrite
+		s.spilled += int64(toWrite)
+		n += toWrite
+
+		if s.writeOff == s.chunkSize {
+			if err := s.rotateWriteFile(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// ensureWriteFile opens a fresh chunk file to append to, if one isn't
+// already open.
+func (s *spillState) ensureWriteFile() error {
+	if s.writeFile != nil {
+		return nil
+	}
+	s.seq++
+	path := filepath.Join(s.dir, fmt.Sprintf("circulis-spill-%06d.bin", s.seq))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	s.writeFile = f
+	s.writeOff = 0
+	return nil
+}
+
+// rotateWriteFile closes the current write chunk, queuing it for pageIn
+// if it holds any data, and clears writeFile so the next write opens a
+// new one.
+func (s *spillState) rotateWriteFile() error {
+	f := s.writeFile
+	if f == nil {
+		return nil
+	}
+	s.writeFile = nil
+	if s.writeOff == 0 {
+		f.Close()
+		os.Remove(f.Name())
+		return nil
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.pending = append(s.pending, spillChunk{path: f.Name(), size: s.writeOff})
+	return nil
+}
+
+// pageIn reads the oldest spilled chunk into buf, which must be at least
+// chunkSize bytes, and returns how many bytes were paged in. It returns
+// (0, nil) if there is nothing spilled to page in.
+func (s *spillState) pageIn(buf []byte) (int, error) {
+	if len(s.pending) == 0 {
+		// nothing queued, but the currently-open write file may hold the
+		// tail end of the spilled data (a chunk that never reached
+		// chunkSize, so writeOverflow never rotated it); flush it so it
+		// becomes readable instead of sitting stuck on disk forever.
+		if err := s.rotateWriteFile(); err != nil {
+			return 0, err
+		}
+	}
+	if len(s.pending) == 0 {
+		return 0, nil
+	}
+	chunk := s.pending[0]
+	s.pending = s.pending[1:]
+
+	f, err := os.Open(chunk.path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(f, buf[:chunk.size])
+	f.Close()
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+
+	s.spilled -= int64(chunk.size)
+	s.toDelete = append(s.toDelete, chunk.path)
+	return n, nil
+}
+
+// compact deletes every chunk file queued by pageIn as fully consumed.
+func (s *spillState) compact() error {
+	var firstErr error
+	for _, path := range s.toDelete {
+		if err := os.Remove(path); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.toDelete = s.toDelete[:0]
+	return firstErr
+}
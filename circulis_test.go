@@ -1,8 +1,15 @@
 package circulis
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 )
 
 // TestConcurrentReadWrite spins up multiple writers and readers to
@@ -73,3 +80,505 @@ func TestConcurrentReadWrite(t *testing.T) {
 	// wait for readers to drain
 	readWG.Wait()
 }
+
+// TestSPSC exercises the lock-free NewSPSC path with exactly one writer
+// and one reader goroutine, run with -race to catch any missing
+// synchronization around head/tail.
+func TestSPSC(t *testing.T) {
+	const (
+		iterations = 20000
+		bufSize    = 128
+	)
+	c := NewSPSC(1024)
+	c.SetBlocking(true)
+
+	pattern := make([]byte, bufSize)
+	for i := range pattern {
+		pattern[i] = byte(i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			if n, err := c.Write(pattern); err != nil {
+				t.Errorf("Write error: %v (wrote %d bytes)", err, n)
+			}
+		}
+		c.Close()
+	}()
+
+	buf := make([]byte, bufSize)
+	for i := 0; i < iterations; i++ {
+		n, err := c.Read(buf)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		if n != len(pattern) {
+			t.Fatalf("Read size %d, want %d", n, len(pattern))
+		}
+		for j := 0; j < n; j++ {
+			if buf[j] != byte(j) {
+				t.Fatalf("Data mismatch at %d: got %d", j, buf[j])
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// TestWriteMsgReadMsg checks that WriteMsg/ReadMsg preserve record
+// boundaries even when messages are smaller than the byte-stream chunks
+// a naive reader might request, and that short destination buffers are
+// rejected without consuming the record.
+func TestWriteMsgReadMsg(t *testing.T) {
+	c := New(64)
+	c.SetBlocking(false)
+
+	msgs := [][]byte{[]byte("a"), []byte("hello"), []byte(""), []byte("circulis")}
+	for _, m := range msgs {
+		if err := c.WriteMsg(m); err != nil {
+			t.Fatalf("WriteMsg(%q): %v", m, err)
+		}
+	}
+
+	n, err := c.Peek()
+	if err != nil {
+		t.Fatalf("Peek: %v", err)
+	}
+	if n != len(msgs[0]) {
+		t.Fatalf("Peek length = %d, want %d", n, len(msgs[0]))
+	}
+
+	// too-small destination must not consume the record
+	if _, err := c.ReadMsgInto(make([]byte, 0)); err != io.ErrShortBuffer {
+		t.Fatalf("ReadMsgInto with short buffer: got %v, want io.ErrShortBuffer", err)
+	}
+
+	for _, want := range msgs {
+		got, err := c.ReadMsg()
+		if err != nil {
+			t.Fatalf("ReadMsg: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("ReadMsg = %q, want %q", got, want)
+		}
+	}
+
+	if _, err := c.ReadMsg(); err != ErrEmpty {
+		t.Fatalf("ReadMsg on drained buffer: got %v, want ErrEmpty", err)
+	}
+
+	c.SetMaxMsgSize(4)
+	if err := c.WriteMsg([]byte("toolarge")); err != ErrMsgTooLarge {
+		t.Fatalf("WriteMsg over max size: got %v, want ErrMsgTooLarge", err)
+	}
+}
+
+// TestReadContextCancel checks that a blocked ReadContext returns promptly
+// with ctx.Err() once its context is cancelled, rather than blocking
+// forever on an empty buffer.
+func TestReadContextCancel(t *testing.T) {
+	c := New(16)
+	c.SetBlocking(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.ReadContext(ctx, make([]byte, 4))
+		done <- err
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Fatalf("ReadContext error = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ReadContext did not return after cancellation")
+	}
+}
+
+// TestWriteDeadline checks that a blocked Write returns os.ErrDeadlineExceeded
+// once the configured write deadline passes.
+func TestWriteDeadline(t *testing.T) {
+	c := New(16)
+	c.SetBlocking(true)
+	c.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+
+	// fill the buffer so the next write blocks
+	if _, err := c.Write(make([]byte, 16)); err != nil {
+		t.Fatalf("initial fill: %v", err)
+	}
+
+	_, err := c.Write(make([]byte, 1))
+	if err != os.ErrDeadlineExceeded {
+		t.Fatalf("Write past deadline = %v, want os.ErrDeadlineExceeded", err)
+	}
+}
+
+// TestBroadcasterFanOut checks that every consumer sees every byte, and
+// that a detached (closed) consumer no longer gates the writer.
+func TestBroadcasterFanOut(t *testing.T) {
+	b := NewBroadcast(16, 2)
+	b.SetBlocking(false)
+
+	data := []byte("hello world!!!!!") // exactly capacity bytes
+	if n, err := b.Write(data); err != nil || n != len(data) {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+
+	buf0 := make([]byte, len(data))
+	c0 := b.Consumer(0)
+	if n, err := c0.Read(buf0); err != nil || !bytes.Equal(buf0[:n], data) {
+		t.Fatalf("consumer 0 Read = %q, %v", buf0[:n], err)
+	}
+
+	// consumer 1 hasn't read yet, so it still gates the writer: the buffer
+	// is full from its perspective.
+	if _, err := b.Write([]byte("x")); err != ErrFull {
+		t.Fatalf("Write while slow consumer gates: got %v, want ErrFull", err)
+	}
+
+	if err := b.Consumer(1).Close(); err != nil {
+		t.Fatalf("Close consumer 1: %v", err)
+	}
+
+	// consumer 1 detached, so it no longer gates the writer.
+	if n, err := b.Write([]byte("x")); err != nil || n != 1 {
+		t.Fatalf("Write after detach = %d, %v", n, err)
+	}
+}
+
+// TestSpillOverflow checks that writes beyond in-memory capacity spill to
+// disk and are transparently paged back in on Read, in order, and that
+// Compact reclaims the chunk files once they've been consumed.
+func TestSpillOverflow(t *testing.T) {
+	c := NewWithSpill(8, SpillConfig{SpillDir: t.TempDir()})
+	c.SetBlocking(false)
+
+	want := make([]byte, 40)
+	for i := range want {
+		want[i] = byte(i)
+	}
+	if n, err := c.Write(want); err != nil || n != len(want) {
+		t.Fatalf("Write = %d, %v, want %d, nil", n, err, len(want))
+	}
+
+	got := make([]byte, 0, len(want))
+	buf := make([]byte, 7) // deliberately not a divisor of 8 or 40
+	for len(got) < len(want) {
+		n, err := c.Read(buf)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("round-tripped data mismatch")
+	}
+
+	if err := c.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	matches, err := filepath.Glob(filepath.Join(c.spill.dir, "circulis-spill-*"))
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("Compact left chunk files behind: %v", matches)
+	}
+}
+
+// TestSpillPreservesOrder checks that once any data has spilled to disk, a
+// later Write keeps spilling instead of sneaking into ring space freed up
+// by an intervening Read — which would let the new bytes be read back
+// ahead of the still-pending spilled ones.
+func TestSpillPreservesOrder(t *testing.T) {
+	c := NewWithSpill(8, SpillConfig{SpillDir: t.TempDir()})
+	c.SetBlocking(false)
+
+	want := make([]byte, 40)
+	for i := range want {
+		want[i] = byte(i)
+	}
+	if n, err := c.Write(want); err != nil || n != len(want) {
+		t.Fatalf("Write = %d, %v, want %d, nil", n, err, len(want))
+	}
+
+	drained := make([]byte, 3)
+	if n, err := c.Read(drained); err != nil || n != 3 {
+		t.Fatalf("Read = %d, %v, want 3, nil", n, err)
+	}
+	want = append(want, 200, 201)
+	if n, err := c.Write([]byte{200, 201}); err != nil || n != 2 {
+		t.Fatalf("Write = %d, %v, want 2, nil", n, err)
+	}
+
+	got := append([]byte{}, drained...)
+	buf := make([]byte, 7)
+	for len(got) < len(want) {
+		n, err := c.Read(buf)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("round-tripped data mismatch: got %v, want %v", got, want)
+	}
+}
+
+// TestSpillMsgPeekPagesIn checks that Peek (and so ReadMsg/ReadMsgInto,
+// which share peekLocked) pages in spilled data instead of reporting
+// ErrEmpty while a full record is sitting in a spill chunk, not yet paged
+// back into the ring.
+func TestSpillMsgPeekPagesIn(t *testing.T) {
+	// capacity 8 == the spill chunk size, so the second frame spills as
+	// exactly one whole, already-rotated chunk.
+	c := NewWithSpill(8, SpillConfig{SpillDir: t.TempDir()})
+	c.SetBlocking(false)
+
+	frame := func(payload string) []byte {
+		var hdr [msgHeaderLen]byte
+		binary.BigEndian.PutUint32(hdr[:], uint32(len(payload)))
+		return append(hdr[:], payload...)
+	}
+	raw := append(frame("msg1"), frame("msg2")...)
+	if n, err := c.Write(raw); err != nil || n != len(raw) {
+		t.Fatalf("Write = %d, %v, want %d, nil", n, err, len(raw))
+	}
+
+	// drain the first frame, which is all that fit in memory, leaving the
+	// second spilled in its own chunk.
+	drain := make([]byte, 8)
+	if n, err := c.Read(drain); err != nil || n != 8 {
+		t.Fatalf("Read = %d, %v, want 8, nil", n, err)
+	}
+
+	n, err := c.Peek()
+	if err != nil {
+		t.Fatalf("Peek after draining in-memory bytes: %v, want nil (spilled data still pending)", err)
+	}
+	if n != len("msg2") {
+		t.Fatalf("Peek = %d, want %d", n, len("msg2"))
+	}
+	got, err := c.ReadMsg()
+	if err != nil || !bytes.Equal(got, []byte("msg2")) {
+		t.Fatalf("ReadMsg = %q, %v, want %q, nil", got, err, "msg2")
+	}
+}
+
+// TestReadFrom checks that ReadFrom drains a larger io.Reader into a
+// smaller ring across multiple wraps, blocking for room as needed.
+func TestReadFrom(t *testing.T) {
+	c := New(8)
+	c.SetBlocking(true)
+
+	want := make([]byte, 30)
+	for i := range want {
+		want[i] = byte(i)
+	}
+
+	type result struct {
+		n   int64
+		err error
+	}
+	rfDone := make(chan result, 1)
+	go func() {
+		n, err := c.ReadFrom(bytes.NewReader(want))
+		rfDone <- result{n, err}
+	}()
+
+	got := make([]byte, 0, len(want))
+	buf := make([]byte, 5)
+	for len(got) < len(want) {
+		n, err := c.Read(buf)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("round-tripped data mismatch")
+	}
+
+	r := <-rfDone
+	if r.err != nil || r.n != int64(len(want)) {
+		t.Fatalf("ReadFrom = %d, %v, want %d, nil", r.n, r.err, len(want))
+	}
+}
+
+// TestWriteTo checks that WriteTo drains a smaller ring into an io.Writer
+// across multiple wraps, stopping cleanly once the buffer is closed.
+func TestWriteTo(t *testing.T) {
+	c := New(8)
+	c.SetBlocking(true)
+
+	want := make([]byte, 30)
+	for i := range want {
+		want[i] = byte(i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if n, err := c.Write(want); err != nil || n != len(want) {
+			t.Errorf("Write = %d, %v", n, err)
+		}
+		c.Close()
+	}()
+
+	var dst bytes.Buffer
+	if _, err := c.WriteTo(&dst); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	wg.Wait()
+
+	if !bytes.Equal(dst.Bytes(), want) {
+		t.Fatalf("round-tripped data mismatch")
+	}
+}
+
+// TestWriterAtReservation checks that a WriterAt reservation is exclusive:
+// a second reservation is rejected, and an ordinary Write cannot allocate
+// into the reserved space (and so can't clobber it), until the
+// reservation is fully written and published.
+func TestWriterAtReservation(t *testing.T) {
+	c := New(8)
+	c.SetBlocking(false)
+
+	wa, err := c.WriterAt(8)
+	if err != nil {
+		t.Fatalf("WriterAt: %v", err)
+	}
+
+	if _, err := c.WriterAt(1); err != ErrReserved {
+		t.Fatalf("second WriterAt: got %v, want ErrReserved", err)
+	}
+
+	if _, err := c.Write([]byte("CLOBBER!")); err != ErrFull {
+		t.Fatalf("Write while reserved: got %v, want ErrFull", err)
+	}
+
+	want := []byte("reserved")
+	if n, err := wa.WriteAt(want, 0); err != nil || n != len(want) {
+		t.Fatalf("WriteAt = %d, %v", n, err)
+	}
+
+	got := make([]byte, len(want))
+	if n, err := c.Read(got); err != nil || n != len(want) {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Read = %q, want %q", got, want)
+	}
+
+	// the reservation is released, so Write now succeeds.
+	if n, err := c.Write([]byte("ok")); err != nil || n != 2 {
+		t.Fatalf("Write after release = %d, %v", n, err)
+	}
+}
+
+// TestGrowable checks that Write grows the buffer instead of blocking or
+// returning ErrFull while under the configured maximum, and that Metrics
+// reflects the growth.
+func TestGrowable(t *testing.T) {
+	c := New(4, WithGrowable(64))
+	c.SetBlocking(false)
+
+	data := make([]byte, 50)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if n, err := c.Write(data); err != nil || n != len(data) {
+		t.Fatalf("Write = %d, %v, want %d, nil", n, err, len(data))
+	}
+
+	got := make([]byte, len(data))
+	if n, err := c.Read(got); err != nil || n != len(data) {
+		t.Fatalf("Read = %d, %v, want %d, nil", n, err, len(data))
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("round-tripped data mismatch")
+	}
+
+	m := c.Metrics()
+	if m.Growths == 0 {
+		t.Fatalf("Metrics().Growths = 0, want > 0")
+	}
+	if m.Capacity < len(data) || m.Capacity > 64 {
+		t.Fatalf("Metrics().Capacity = %d, want in [%d, 64]", m.Capacity, len(data))
+	}
+	if m.BytesWritten != uint64(len(data)) {
+		t.Fatalf("Metrics().BytesWritten = %d, want %d", m.BytesWritten, len(data))
+	}
+
+	// a write taking a second buffer past its growMax must still return
+	// ErrFull rather than grow further.
+	c2 := New(4, WithGrowable(8))
+	if _, err := c2.Write(make([]byte, 9)); err != ErrFull {
+		t.Fatalf("Write past growMax: got %v, want ErrFull", err)
+	}
+}
+
+// TestGrowableReadFrom checks that ReadFrom, like Write, grows a
+// WithGrowable buffer instead of blocking or returning ErrFull once the
+// ring is full.
+func TestGrowableReadFrom(t *testing.T) {
+	c := New(4, WithGrowable(64))
+	c.SetBlocking(false)
+
+	data := make([]byte, 50)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	n, err := c.ReadFrom(bytes.NewReader(data))
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("ReadFrom = %d, %v, want %d, nil", n, err, len(data))
+	}
+
+	got := make([]byte, len(data))
+	if n, err := c.Read(got); err != nil || n != len(data) {
+		t.Fatalf("Read = %d, %v, want %d, nil", n, err, len(data))
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("round-tripped data mismatch")
+	}
+	if c.Metrics().Growths == 0 {
+		t.Fatalf("Metrics().Growths = 0, want > 0")
+	}
+}
+
+// TestGrowableWriterAt checks that WriterAt, like Write, grows a
+// WithGrowable buffer instead of returning ErrFull when the requested
+// reservation doesn't fit yet but would once grown.
+func TestGrowableWriterAt(t *testing.T) {
+	c := New(4, WithGrowable(64))
+
+	data := make([]byte, 50)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	wa, err := c.WriterAt(len(data))
+	if err != nil {
+		t.Fatalf("WriterAt: %v, want nil (should grow to fit)", err)
+	}
+	if n, err := wa.WriteAt(data, 0); err != nil || n != len(data) {
+		t.Fatalf("WriteAt = %d, %v, want %d, nil", n, err, len(data))
+	}
+
+	got := make([]byte, len(data))
+	c.SetBlocking(false)
+	if n, err := c.Read(got); err != nil || n != len(data) {
+		t.Fatalf("Read = %d, %v, want %d, nil", n, err, len(data))
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("round-tripped data mismatch")
+	}
+	if c.Metrics().Growths == 0 {
+		t.Fatalf("Metrics().Growths = 0, want > 0")
+	}
+}